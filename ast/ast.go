@@ -0,0 +1,1083 @@
+// Package ast implements the lexer, parser, and evaluator for the
+// expression language, independent of any particular front end (REPL,
+// JIT, etc).
+package ast
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	TokenEOF                = "EOF"
+	TokenIllegal            = "ILLEGAL"
+	TokenUnterminatedString = "UNTERMINATED_STRING"
+	TokenNumber             = "NUMBER"
+	TokenString             = "STRING"
+	TokenIdent              = "IDENT"
+	TokenTrue               = "TRUE"
+	TokenFalse              = "FALSE"
+	TokenAssign             = "="
+	TokenComma              = ","
+	TokenPlus               = "+"
+	TokenMinus              = "-"
+	TokenMultiply           = "*"
+	TokenDivide             = "/"
+	TokenLParen             = "("
+	TokenRParen             = ")"
+	TokenEq                 = "=="
+	TokenNotEq              = "!="
+	TokenLt                 = "<"
+	TokenLtEq               = "<="
+	TokenGt                 = ">"
+	TokenGtEq               = ">="
+	TokenAnd                = "&&"
+	TokenOr                 = "||"
+	TokenNot                = "!"
+	TokenQuestion           = "?"
+	TokenColon              = ":"
+	TokenPow                = "**"
+	TokenMod                = "%"
+	TokenBitAnd             = "&"
+	TokenBitOr              = "|"
+	TokenBitXor             = "^"
+	TokenShl                = "<<"
+	TokenShr                = ">>"
+)
+
+// Pos identifies a location in the source text.
+type Pos struct {
+	Line   int
+	Column int
+}
+
+type Token struct {
+	Type  string
+	Value string
+	Pos   Pos
+}
+
+// ParseError is returned by the parser when source text cannot be
+// turned into an AST.
+type ParseError struct {
+	Pos   Pos
+	Msg   string
+	Token Token
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%d:%d: %s near '%s'", e.Pos.Line, e.Pos.Column, e.Msg, e.Token.Value)
+}
+
+// EvalError is returned when a well-formed AST fails to evaluate, e.g.
+// division by zero or applying an operator to the wrong types.
+type EvalError struct {
+	Pos Pos
+	Msg string
+}
+
+func (e *EvalError) Error() string {
+	return fmt.Sprintf("%d:%d: %s", e.Pos.Line, e.Pos.Column, e.Msg)
+}
+
+type Lexer struct {
+	input        string
+	position     int
+	readPosition int
+	ch           rune
+	line         int
+	col          int
+}
+
+func NewLexer(input string) *Lexer {
+	l := &Lexer{input: input, line: 1}
+	l.readChar()
+	return l
+}
+
+func (l *Lexer) readChar() {
+	if l.readPosition >= len(l.input) {
+		l.ch = 0
+	} else {
+		l.ch = rune(l.input[l.readPosition])
+	}
+	l.position = l.readPosition
+	l.readPosition++
+
+	if l.ch == '\n' {
+		l.line++
+		l.col = 0
+	} else {
+		l.col++
+	}
+}
+
+func (l *Lexer) peekChar() rune {
+	if l.readPosition >= len(l.input) {
+		return 0
+	}
+	return rune(l.input[l.readPosition])
+}
+
+func (l *Lexer) skipWhitespace() {
+	for unicode.IsSpace(l.ch) {
+		l.readChar()
+	}
+}
+
+func (l *Lexer) NextToken() Token {
+	l.skipWhitespace()
+
+	pos := Pos{Line: l.line, Column: l.col}
+	var tok Token
+
+	switch l.ch {
+	case '+':
+		tok = Token{Type: TokenPlus, Value: string(l.ch)}
+	case '-':
+		tok = Token{Type: TokenMinus, Value: string(l.ch)}
+	case '*':
+		if l.peekChar() == '*' {
+			l.readChar()
+			tok = Token{Type: TokenPow, Value: "**"}
+		} else {
+			tok = Token{Type: TokenMultiply, Value: "*"}
+		}
+	case '/':
+		tok = Token{Type: TokenDivide, Value: string(l.ch)}
+	case '%':
+		tok = Token{Type: TokenMod, Value: string(l.ch)}
+	case '^':
+		tok = Token{Type: TokenBitXor, Value: string(l.ch)}
+	case '(':
+		tok = Token{Type: TokenLParen, Value: string(l.ch)}
+	case ')':
+		tok = Token{Type: TokenRParen, Value: string(l.ch)}
+	case ',':
+		tok = Token{Type: TokenComma, Value: string(l.ch)}
+	case '?':
+		tok = Token{Type: TokenQuestion, Value: string(l.ch)}
+	case ':':
+		tok = Token{Type: TokenColon, Value: string(l.ch)}
+	case '=':
+		if l.peekChar() == '=' {
+			l.readChar()
+			tok = Token{Type: TokenEq, Value: "=="}
+		} else {
+			tok = Token{Type: TokenAssign, Value: "="}
+		}
+	case '!':
+		if l.peekChar() == '=' {
+			l.readChar()
+			tok = Token{Type: TokenNotEq, Value: "!="}
+		} else {
+			tok = Token{Type: TokenNot, Value: "!"}
+		}
+	case '<':
+		if l.peekChar() == '=' {
+			l.readChar()
+			tok = Token{Type: TokenLtEq, Value: "<="}
+		} else if l.peekChar() == '<' {
+			l.readChar()
+			tok = Token{Type: TokenShl, Value: "<<"}
+		} else {
+			tok = Token{Type: TokenLt, Value: "<"}
+		}
+	case '>':
+		if l.peekChar() == '=' {
+			l.readChar()
+			tok = Token{Type: TokenGtEq, Value: ">="}
+		} else if l.peekChar() == '>' {
+			l.readChar()
+			tok = Token{Type: TokenShr, Value: ">>"}
+		} else {
+			tok = Token{Type: TokenGt, Value: ">"}
+		}
+	case '&':
+		if l.peekChar() == '&' {
+			l.readChar()
+			tok = Token{Type: TokenAnd, Value: "&&"}
+		} else {
+			tok = Token{Type: TokenBitAnd, Value: "&"}
+		}
+	case '|':
+		if l.peekChar() == '|' {
+			l.readChar()
+			tok = Token{Type: TokenOr, Value: "||"}
+		} else {
+			tok = Token{Type: TokenBitOr, Value: "|"}
+		}
+	case '"':
+		value, terminated := l.readString()
+		tok.Value = value
+		tok.Pos = pos
+		if !terminated {
+			tok.Type = TokenUnterminatedString
+			return tok
+		}
+		tok.Type = TokenString
+		l.readChar() // consume closing quote
+		return tok
+	case 0:
+		tok = Token{Type: TokenEOF, Value: "", Pos: pos}
+		return tok
+	default:
+		if unicode.IsDigit(l.ch) || l.ch == '.' {
+			tok.Type = TokenNumber
+			tok.Value = l.readNumber()
+			tok.Pos = pos
+			return tok
+		} else if isIdentStart(l.ch) {
+			tok.Value = l.readIdentifier()
+			tok.Type = lookupIdent(tok.Value)
+			tok.Pos = pos
+			return tok
+		}
+		tok = Token{Type: TokenIllegal, Value: string(l.ch)}
+	}
+
+	tok.Pos = pos
+	l.readChar()
+	return tok
+}
+
+func (l *Lexer) readNumber() string {
+	startPos := l.position
+	for unicode.IsDigit(l.ch) || l.ch == '.' {
+		l.readChar()
+	}
+	return l.input[startPos:l.position]
+}
+
+func (l *Lexer) readIdentifier() string {
+	startPos := l.position
+	for isIdentStart(l.ch) || unicode.IsDigit(l.ch) {
+		l.readChar()
+	}
+	return l.input[startPos:l.position]
+}
+
+// readString consumes the body of a string literal, translating the
+// standard backslash escapes, and leaves l.ch on the closing quote (or
+// 0 if the string was never closed). The second return value reports
+// whether a closing quote was actually found, so callers can tell an
+// unterminated literal apart from one that simply ends in an escape.
+func (l *Lexer) readString() (string, bool) {
+	var sb strings.Builder
+	l.readChar() // skip opening quote
+
+	for l.ch != '"' && l.ch != 0 {
+		if l.ch == '\\' {
+			l.readChar()
+			switch l.ch {
+			case 'n':
+				sb.WriteRune('\n')
+			case 't':
+				sb.WriteRune('\t')
+			case '"':
+				sb.WriteRune('"')
+			case '\\':
+				sb.WriteRune('\\')
+			default:
+				sb.WriteRune(l.ch)
+			}
+		} else {
+			sb.WriteRune(l.ch)
+		}
+		l.readChar()
+	}
+
+	return sb.String(), l.ch == '"'
+}
+
+func isIdentStart(ch rune) bool {
+	return unicode.IsLetter(ch) || ch == '_'
+}
+
+func lookupIdent(ident string) string {
+	switch ident {
+	case "true":
+		return TokenTrue
+	case "false":
+		return TokenFalse
+	default:
+		return TokenIdent
+	}
+}
+
+// ValueType identifies the dynamic type of a Value.
+type ValueType int
+
+const (
+	TypeInt ValueType = iota
+	TypeFloat
+	TypeBool
+	TypeString
+)
+
+func (t ValueType) String() string {
+	switch t {
+	case TypeInt:
+		return "int"
+	case TypeFloat:
+		return "float"
+	case TypeBool:
+		return "bool"
+	case TypeString:
+		return "string"
+	default:
+		return "unknown"
+	}
+}
+
+// Value is the tagged union produced by evaluating a Node.
+type Value struct {
+	Type  ValueType
+	Int   int64
+	Float float64
+	Bool  bool
+	Str   string
+}
+
+func IntValue(i int64) Value     { return Value{Type: TypeInt, Int: i} }
+func FloatValue(f float64) Value { return Value{Type: TypeFloat, Float: f} }
+func BoolValue(b bool) Value     { return Value{Type: TypeBool, Bool: b} }
+func StringValue(s string) Value { return Value{Type: TypeString, Str: s} }
+
+func (v Value) String() string {
+	switch v.Type {
+	case TypeInt:
+		return strconv.FormatInt(v.Int, 10)
+	case TypeFloat:
+		return strconv.FormatFloat(v.Float, 'g', -1, 64)
+	case TypeBool:
+		return strconv.FormatBool(v.Bool)
+	case TypeString:
+		return v.Str
+	default:
+		return "<invalid>"
+	}
+}
+
+func (v Value) isNumeric() bool {
+	return v.Type == TypeInt || v.Type == TypeFloat
+}
+
+// asFloat returns v's numeric value promoted to float64. Callers must
+// check isNumeric first.
+func (v Value) asFloat() float64 {
+	if v.Type == TypeInt {
+		return float64(v.Int)
+	}
+	return v.Float
+}
+
+func valuesEqual(a, b Value) bool {
+	if a.isNumeric() && b.isNumeric() {
+		return a.asFloat() == b.asFloat()
+	}
+	if a.Type != b.Type {
+		return false
+	}
+	switch a.Type {
+	case TypeBool:
+		return a.Bool == b.Bool
+	case TypeString:
+		return a.Str == b.Str
+	default:
+		return false
+	}
+}
+
+// Builtin is a function pre-registered in an Env, with an argument count
+// it requires; MaxArgs of -1 means no upper bound. Built-ins operate on
+// plain float64s; CallNode is responsible for checking that arguments
+// are numeric before unwrapping them.
+type Builtin struct {
+	MinArgs int
+	MaxArgs int
+	Fn      func(args []float64) float64
+}
+
+// Env holds the variables and built-in functions visible to a running
+// expression, shared across the lifetime of a REPL session.
+type Env struct {
+	vars  map[string]Value
+	funcs map[string]Builtin
+}
+
+// NewEnv returns an Env pre-populated with the standard constants and
+// built-in functions.
+func NewEnv() *Env {
+	return &Env{
+		vars: map[string]Value{
+			"pi": FloatValue(math.Pi),
+			"e":  FloatValue(math.E),
+		},
+		funcs: map[string]Builtin{
+			"sqrt": {1, 1, func(args []float64) float64 { return math.Sqrt(args[0]) }},
+			"sin":  {1, 1, func(args []float64) float64 { return math.Sin(args[0]) }},
+			"cos":  {1, 1, func(args []float64) float64 { return math.Cos(args[0]) }},
+			"log":  {1, 1, func(args []float64) float64 { return math.Log(args[0]) }},
+			"abs":  {1, 1, func(args []float64) float64 { return math.Abs(args[0]) }},
+			"pow":  {2, 2, func(args []float64) float64 { return math.Pow(args[0], args[1]) }},
+			"min": {1, -1, func(args []float64) float64 {
+				m := args[0]
+				for _, v := range args[1:] {
+					m = math.Min(m, v)
+				}
+				return m
+			}},
+			"max": {1, -1, func(args []float64) float64 {
+				m := args[0]
+				for _, v := range args[1:] {
+					m = math.Max(m, v)
+				}
+				return m
+			}},
+		},
+	}
+}
+
+type Node interface {
+	Eval(env *Env) (Value, error)
+}
+
+// LiteralNode is a constant value parsed directly from source: a
+// number, string, or boolean.
+type LiteralNode struct {
+	Val Value
+	Pos Pos
+}
+
+func (n *LiteralNode) Eval(env *Env) (Value, error) {
+	return n.Val, nil
+}
+
+type IdentNode struct {
+	Name string
+	Pos  Pos
+}
+
+func (n *IdentNode) Eval(env *Env) (Value, error) {
+	if v, ok := env.vars[n.Name]; ok {
+		return v, nil
+	}
+	return Value{}, &EvalError{Pos: n.Pos, Msg: fmt.Sprintf("undefined variable: %s", n.Name)}
+}
+
+type AssignNode struct {
+	Name  string
+	Value Node
+	Pos   Pos
+}
+
+func (n *AssignNode) Eval(env *Env) (Value, error) {
+	v, err := n.Value.Eval(env)
+	if err != nil {
+		return Value{}, err
+	}
+	env.vars[n.Name] = v
+	return v, nil
+}
+
+type CallNode struct {
+	Name string
+	Args []Node
+	Pos  Pos
+}
+
+func (n *CallNode) Eval(env *Env) (Value, error) {
+	fn, ok := env.funcs[n.Name]
+	if !ok {
+		return Value{}, &EvalError{Pos: n.Pos, Msg: fmt.Sprintf("undefined function: %s", n.Name)}
+	}
+	if len(n.Args) < fn.MinArgs || (fn.MaxArgs >= 0 && len(n.Args) > fn.MaxArgs) {
+		return Value{}, &EvalError{Pos: n.Pos, Msg: fmt.Sprintf("wrong number of arguments to %s", n.Name)}
+	}
+
+	args := make([]float64, len(n.Args))
+	for i, a := range n.Args {
+		v, err := a.Eval(env)
+		if err != nil {
+			return Value{}, err
+		}
+		if !v.isNumeric() {
+			return Value{}, &EvalError{Pos: n.Pos, Msg: fmt.Sprintf("argument %d to %s must be numeric, got %s", i+1, n.Name, v.Type)}
+		}
+		args[i] = v.asFloat()
+	}
+	return FloatValue(fn.Fn(args)), nil
+}
+
+// BinaryOpNode covers arithmetic, equality, and ordering comparisons.
+// Logical && and || are handled separately by LogicalOpNode so they can
+// short-circuit.
+type BinaryOpNode struct {
+	Left     Node
+	Operator string
+	Right    Node
+	Pos      Pos
+}
+
+func (n *BinaryOpNode) Eval(env *Env) (Value, error) {
+	left, err := n.Left.Eval(env)
+	if err != nil {
+		return Value{}, err
+	}
+	right, err := n.Right.Eval(env)
+	if err != nil {
+		return Value{}, err
+	}
+
+	switch n.Operator {
+	case TokenPlus:
+		if left.Type == TypeString && right.Type == TypeString {
+			return StringValue(left.Str + right.Str), nil
+		}
+		if left.isNumeric() && right.isNumeric() {
+			return addNumeric(left, right), nil
+		}
+		return Value{}, n.typeError(left, right)
+	case TokenMinus, TokenMultiply:
+		if !left.isNumeric() || !right.isNumeric() {
+			return Value{}, n.typeError(left, right)
+		}
+		return arithNumeric(n.Operator, left, right), nil
+	case TokenDivide:
+		if !left.isNumeric() || !right.isNumeric() {
+			return Value{}, n.typeError(left, right)
+		}
+		rf := right.asFloat()
+		if rf == 0 {
+			return Value{}, &EvalError{Pos: n.Pos, Msg: "division by zero"}
+		}
+		return FloatValue(left.asFloat() / rf), nil
+	case TokenEq:
+		return BoolValue(valuesEqual(left, right)), nil
+	case TokenNotEq:
+		return BoolValue(!valuesEqual(left, right)), nil
+	case TokenLt, TokenLtEq, TokenGt, TokenGtEq:
+		if !left.isNumeric() || !right.isNumeric() {
+			return Value{}, n.typeError(left, right)
+		}
+		lf, rf := left.asFloat(), right.asFloat()
+		switch n.Operator {
+		case TokenLt:
+			return BoolValue(lf < rf), nil
+		case TokenLtEq:
+			return BoolValue(lf <= rf), nil
+		case TokenGt:
+			return BoolValue(lf > rf), nil
+		default:
+			return BoolValue(lf >= rf), nil
+		}
+	case TokenPow:
+		if !left.isNumeric() || !right.isNumeric() {
+			return Value{}, n.typeError(left, right)
+		}
+		return FloatValue(math.Pow(left.asFloat(), right.asFloat())), nil
+	case TokenMod:
+		if left.Type != TypeInt || right.Type != TypeInt {
+			return Value{}, n.typeError(left, right)
+		}
+		if right.Int == 0 {
+			return Value{}, &EvalError{Pos: n.Pos, Msg: "division by zero"}
+		}
+		return IntValue(left.Int % right.Int), nil
+	case TokenBitAnd, TokenBitOr, TokenBitXor, TokenShl, TokenShr:
+		if left.Type != TypeInt || right.Type != TypeInt {
+			return Value{}, n.typeError(left, right)
+		}
+		switch n.Operator {
+		case TokenBitAnd:
+			return IntValue(left.Int & right.Int), nil
+		case TokenBitOr:
+			return IntValue(left.Int | right.Int), nil
+		case TokenBitXor:
+			return IntValue(left.Int ^ right.Int), nil
+		case TokenShl:
+			return IntValue(left.Int << uint(right.Int)), nil
+		default:
+			return IntValue(left.Int >> uint(right.Int)), nil
+		}
+	default:
+		return Value{}, &EvalError{Pos: n.Pos, Msg: fmt.Sprintf("unknown operator: %s", n.Operator)}
+	}
+}
+
+func (n *BinaryOpNode) typeError(a, b Value) error {
+	return &EvalError{Pos: n.Pos, Msg: fmt.Sprintf("cannot apply '%s' to %s and %s", n.Operator, a.Type, b.Type)}
+}
+
+func addNumeric(a, b Value) Value {
+	if a.Type == TypeInt && b.Type == TypeInt {
+		return IntValue(a.Int + b.Int)
+	}
+	return FloatValue(a.asFloat() + b.asFloat())
+}
+
+func arithNumeric(op string, a, b Value) Value {
+	if a.Type == TypeInt && b.Type == TypeInt {
+		if op == TokenMinus {
+			return IntValue(a.Int - b.Int)
+		}
+		return IntValue(a.Int * b.Int)
+	}
+	if op == TokenMinus {
+		return FloatValue(a.asFloat() - b.asFloat())
+	}
+	return FloatValue(a.asFloat() * b.asFloat())
+}
+
+// LogicalOpNode implements short-circuiting && and ||: the right-hand
+// side is only evaluated when its value could change the result.
+type LogicalOpNode struct {
+	Left     Node
+	Operator string
+	Right    Node
+	Pos      Pos
+}
+
+func (n *LogicalOpNode) Eval(env *Env) (Value, error) {
+	left, err := n.Left.Eval(env)
+	if err != nil {
+		return Value{}, err
+	}
+	if left.Type != TypeBool {
+		return Value{}, &EvalError{Pos: n.Pos, Msg: fmt.Sprintf("cannot apply '%s' to %s", n.Operator, left.Type)}
+	}
+
+	if n.Operator == TokenAnd && !left.Bool {
+		return BoolValue(false), nil
+	}
+	if n.Operator == TokenOr && left.Bool {
+		return BoolValue(true), nil
+	}
+
+	right, err := n.Right.Eval(env)
+	if err != nil {
+		return Value{}, err
+	}
+	if right.Type != TypeBool {
+		return Value{}, &EvalError{Pos: n.Pos, Msg: fmt.Sprintf("cannot apply '%s' to %s and %s", n.Operator, left.Type, right.Type)}
+	}
+	return right, nil
+}
+
+type UnaryOpNode struct {
+	Operator string
+	Right    Node
+	Pos      Pos
+}
+
+func (n *UnaryOpNode) Eval(env *Env) (Value, error) {
+	right, err := n.Right.Eval(env)
+	if err != nil {
+		return Value{}, err
+	}
+
+	switch n.Operator {
+	case TokenMinus:
+		switch right.Type {
+		case TypeInt:
+			return IntValue(-right.Int), nil
+		case TypeFloat:
+			return FloatValue(-right.Float), nil
+		default:
+			return Value{}, &EvalError{Pos: n.Pos, Msg: fmt.Sprintf("cannot apply '-' to %s", right.Type)}
+		}
+	case TokenNot:
+		if right.Type != TypeBool {
+			return Value{}, &EvalError{Pos: n.Pos, Msg: fmt.Sprintf("cannot apply '!' to %s", right.Type)}
+		}
+		return BoolValue(!right.Bool), nil
+	default:
+		return Value{}, &EvalError{Pos: n.Pos, Msg: fmt.Sprintf("unknown operator: %s", n.Operator)}
+	}
+}
+
+// TernaryNode implements `cond ? then : else`.
+type TernaryNode struct {
+	Cond Node
+	Then Node
+	Else Node
+	Pos  Pos
+}
+
+func (n *TernaryNode) Eval(env *Env) (Value, error) {
+	cond, err := n.Cond.Eval(env)
+	if err != nil {
+		return Value{}, err
+	}
+	if cond.Type != TypeBool {
+		return Value{}, &EvalError{Pos: n.Pos, Msg: fmt.Sprintf("ternary condition must be bool, got %s", cond.Type)}
+	}
+	if cond.Bool {
+		return n.Then.Eval(env)
+	}
+	return n.Else.Eval(env)
+}
+
+// Assoc is the associativity of a registered infix operator.
+type Assoc int
+
+const (
+	AssocLeft Assoc = iota
+	AssocRight
+)
+
+// Binding powers for the Pratt core, covering everything from bitwise
+// operators through exponentiation. Higher binds tighter. The layers
+// above the Pratt core (ternary, logical, equality, comparison) use
+// their own fixed recursive-descent precedence, each looser than
+// anything registered here.
+const (
+	bpBitOr   = 5
+	bpBitXor  = 6
+	bpBitAnd  = 7
+	bpShift   = 8
+	bpSum     = 10
+	bpProduct = 20
+	bpUnary   = 25
+	bpPow     = 30
+)
+
+type infixEntry struct {
+	bp    int
+	assoc Assoc
+	fn    func(left, right Node) Node
+}
+
+type Parser struct {
+	lexer   *Lexer
+	curTok  Token
+	peekTok Token
+
+	// opPos is the position of the infix operator currently being
+	// reduced; built-in infix functions close over the parser to read
+	// it, since RegisterInfix's fn signature takes only the operands.
+	opPos Pos
+
+	prefixFns map[string]func() (Node, error)
+	infixFns  map[string]infixEntry
+}
+
+func NewParser(lexer *Lexer) *Parser {
+	p := &Parser{lexer: lexer}
+	p.advance()
+	p.advance()
+
+	p.prefixFns = map[string]func() (Node, error){
+		TokenNumber: p.parseNumberLiteral,
+		TokenString: p.parseStringLiteral,
+		TokenTrue:   p.parseBoolLiteral,
+		TokenFalse:  p.parseBoolLiteral,
+		TokenIdent:  p.parseIdentOrCall,
+		TokenLParen: p.parseGroup,
+		TokenMinus:  p.parseUnaryPrefix,
+		TokenNot:    p.parseUnaryPrefix,
+	}
+
+	p.infixFns = map[string]infixEntry{}
+	binOp := func(op string, pos Pos, left, right Node) Node {
+		return &BinaryOpNode{Left: left, Operator: op, Right: right, Pos: pos}
+	}
+	p.RegisterInfix(TokenPlus, bpSum, AssocLeft, func(l, r Node) Node { return binOp(TokenPlus, p.opPos, l, r) })
+	p.RegisterInfix(TokenMinus, bpSum, AssocLeft, func(l, r Node) Node { return binOp(TokenMinus, p.opPos, l, r) })
+	p.RegisterInfix(TokenMultiply, bpProduct, AssocLeft, func(l, r Node) Node { return binOp(TokenMultiply, p.opPos, l, r) })
+	p.RegisterInfix(TokenDivide, bpProduct, AssocLeft, func(l, r Node) Node { return binOp(TokenDivide, p.opPos, l, r) })
+	p.RegisterInfix(TokenMod, bpProduct, AssocLeft, func(l, r Node) Node { return binOp(TokenMod, p.opPos, l, r) })
+	p.RegisterInfix(TokenPow, bpPow, AssocRight, func(l, r Node) Node { return binOp(TokenPow, p.opPos, l, r) })
+	p.RegisterInfix(TokenBitOr, bpBitOr, AssocLeft, func(l, r Node) Node { return binOp(TokenBitOr, p.opPos, l, r) })
+	p.RegisterInfix(TokenBitXor, bpBitXor, AssocLeft, func(l, r Node) Node { return binOp(TokenBitXor, p.opPos, l, r) })
+	p.RegisterInfix(TokenBitAnd, bpBitAnd, AssocLeft, func(l, r Node) Node { return binOp(TokenBitAnd, p.opPos, l, r) })
+	p.RegisterInfix(TokenShl, bpShift, AssocLeft, func(l, r Node) Node { return binOp(TokenShl, p.opPos, l, r) })
+	p.RegisterInfix(TokenShr, bpShift, AssocLeft, func(l, r Node) Node { return binOp(TokenShr, p.opPos, l, r) })
+
+	return p
+}
+
+// RegisterInfix adds or replaces an infix operator, so library users can
+// extend the grammar from outside the package without touching the
+// parser core.
+func (p *Parser) RegisterInfix(tok string, bp int, assoc Assoc, fn func(left, right Node) Node) {
+	p.infixFns[tok] = infixEntry{bp: bp, assoc: assoc, fn: fn}
+}
+
+func (p *Parser) advance() {
+	p.curTok = p.peekTok
+	p.peekTok = p.lexer.NextToken()
+}
+
+func (p *Parser) errorf(format string, args ...interface{}) error {
+	return &ParseError{Pos: p.curTok.Pos, Msg: fmt.Sprintf(format, args...), Token: p.curTok}
+}
+
+// Parse parses a single top-level expression or assignment and checks
+// that it consumes the whole input.
+func (p *Parser) Parse() (Node, error) {
+	node, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	if p.curTok.Type != TokenEOF {
+		return nil, p.errorf("unexpected token at end of input")
+	}
+	return node, nil
+}
+
+// parseExpression parses a top-level expression, which may be a plain
+// expression or a `name = expr` assignment. Precedence from here down:
+// ternary -> logical-or -> logical-and -> equality -> comparison ->
+// the Pratt core (arithmetic, bitwise, unary, primary).
+func (p *Parser) parseExpression() (Node, error) {
+	if p.curTok.Type == TokenIdent && p.peekTok.Type == TokenAssign {
+		name := p.curTok.Value
+		pos := p.curTok.Pos
+		p.advance() // consume ident
+		p.advance() // consume '='
+		value, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		return &AssignNode{Name: name, Value: value, Pos: pos}, nil
+	}
+	return p.parseTernary()
+}
+
+func (p *Parser) parseTernary() (Node, error) {
+	cond, err := p.parseLogicalOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.curTok.Type != TokenQuestion {
+		return cond, nil
+	}
+
+	pos := p.curTok.Pos
+	p.advance()
+	thenExpr, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	if p.curTok.Type != TokenColon {
+		return nil, p.errorf("expected ':'")
+	}
+	p.advance()
+	elseExpr, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	return &TernaryNode{Cond: cond, Then: thenExpr, Else: elseExpr, Pos: pos}, nil
+}
+
+func (p *Parser) parseLogicalOr() (Node, error) {
+	node, err := p.parseLogicalAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.curTok.Type == TokenOr {
+		pos := p.curTok.Pos
+		p.advance()
+		right, err := p.parseLogicalAnd()
+		if err != nil {
+			return nil, err
+		}
+		node = &LogicalOpNode{Left: node, Operator: TokenOr, Right: right, Pos: pos}
+	}
+
+	return node, nil
+}
+
+func (p *Parser) parseLogicalAnd() (Node, error) {
+	node, err := p.parseEquality()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.curTok.Type == TokenAnd {
+		pos := p.curTok.Pos
+		p.advance()
+		right, err := p.parseEquality()
+		if err != nil {
+			return nil, err
+		}
+		node = &LogicalOpNode{Left: node, Operator: TokenAnd, Right: right, Pos: pos}
+	}
+
+	return node, nil
+}
+
+func (p *Parser) parseEquality() (Node, error) {
+	node, err := p.parseComparison()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.curTok.Type == TokenEq || p.curTok.Type == TokenNotEq {
+		op, pos := p.curTok.Type, p.curTok.Pos
+		p.advance()
+		right, err := p.parseComparison()
+		if err != nil {
+			return nil, err
+		}
+		node = &BinaryOpNode{Left: node, Operator: op, Right: right, Pos: pos}
+	}
+
+	return node, nil
+}
+
+func (p *Parser) parseComparison() (Node, error) {
+	node, err := p.parsePratt(0)
+	if err != nil {
+		return nil, err
+	}
+
+	for p.curTok.Type == TokenLt || p.curTok.Type == TokenLtEq ||
+		p.curTok.Type == TokenGt || p.curTok.Type == TokenGtEq {
+		op, pos := p.curTok.Type, p.curTok.Pos
+		p.advance()
+		right, err := p.parsePratt(0)
+		if err != nil {
+			return nil, err
+		}
+		node = &BinaryOpNode{Left: node, Operator: op, Right: right, Pos: pos}
+	}
+
+	return node, nil
+}
+
+// parsePratt is a Pratt (operator-precedence) parser over the
+// prefix/infix tables built in NewParser and extended via RegisterInfix.
+// minBp is the lowest binding power an infix operator must have to be
+// consumed at this recursion level. The right operand is parsed with
+// minBp = bp+1 for left-associative operators and bp for right-associative
+// ones, which is how associativity is encoded.
+func (p *Parser) parsePratt(minBp int) (Node, error) {
+	prefix, ok := p.prefixFns[p.curTok.Type]
+	if !ok {
+		if p.curTok.Type == TokenUnterminatedString {
+			return nil, p.errorf("unterminated string literal")
+		}
+		return nil, p.errorf("expected expression")
+	}
+	left, err := prefix()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		entry, ok := p.infixFns[p.curTok.Type]
+		if !ok || entry.bp < minBp {
+			break
+		}
+
+		p.opPos = p.curTok.Pos
+		p.advance()
+
+		nextMinBp := entry.bp + 1
+		if entry.assoc == AssocRight {
+			nextMinBp = entry.bp
+		}
+		right, err := p.parsePratt(nextMinBp)
+		if err != nil {
+			return nil, err
+		}
+		left = entry.fn(left, right)
+	}
+
+	return left, nil
+}
+
+func (p *Parser) parseUnaryPrefix() (Node, error) {
+	op, pos := p.curTok.Type, p.curTok.Pos
+	p.advance()
+	right, err := p.parsePratt(bpUnary)
+	if err != nil {
+		return nil, err
+	}
+	return &UnaryOpNode{Operator: op, Right: right, Pos: pos}, nil
+}
+
+func (p *Parser) parseNumberLiteral() (Node, error) {
+	pos, lit := p.curTok.Pos, p.curTok.Value
+	var val Value
+	if strings.Contains(lit, ".") {
+		f, err := strconv.ParseFloat(lit, 64)
+		if err != nil {
+			return nil, &ParseError{Pos: pos, Msg: "invalid number literal", Token: p.curTok}
+		}
+		val = FloatValue(f)
+	} else {
+		i, err := strconv.ParseInt(lit, 10, 64)
+		if err != nil {
+			return nil, &ParseError{Pos: pos, Msg: "invalid number literal", Token: p.curTok}
+		}
+		val = IntValue(i)
+	}
+	node := &LiteralNode{Val: val, Pos: pos}
+	p.advance()
+	return node, nil
+}
+
+func (p *Parser) parseStringLiteral() (Node, error) {
+	node := &LiteralNode{Val: StringValue(p.curTok.Value), Pos: p.curTok.Pos}
+	p.advance()
+	return node, nil
+}
+
+func (p *Parser) parseBoolLiteral() (Node, error) {
+	node := &LiteralNode{Val: BoolValue(p.curTok.Type == TokenTrue), Pos: p.curTok.Pos}
+	p.advance()
+	return node, nil
+}
+
+func (p *Parser) parseIdentOrCall() (Node, error) {
+	name, pos := p.curTok.Value, p.curTok.Pos
+	if p.peekTok.Type != TokenLParen {
+		p.advance()
+		return &IdentNode{Name: name, Pos: pos}, nil
+	}
+
+	p.advance() // consume ident
+	p.advance() // consume '('
+	var args []Node
+	if p.curTok.Type != TokenRParen {
+		arg, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		for p.curTok.Type == TokenComma {
+			p.advance()
+			arg, err := p.parseExpression()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+		}
+	}
+	if p.curTok.Type != TokenRParen {
+		return nil, p.errorf("expected ')'")
+	}
+	p.advance()
+	return &CallNode{Name: name, Args: args, Pos: pos}, nil
+}
+
+func (p *Parser) parseGroup() (Node, error) {
+	p.advance()
+	node, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	if p.curTok.Type != TokenRParen {
+		return nil, p.errorf("expected ')'")
+	}
+	p.advance()
+	return node, nil
+}
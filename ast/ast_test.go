@@ -0,0 +1,72 @@
+package ast
+
+import "testing"
+
+func evalSource(t *testing.T, src string) Value {
+	t.Helper()
+	parser := NewParser(NewLexer(src))
+	node, err := parser.Parse()
+	if err != nil {
+		t.Fatalf("parse(%q): %v", src, err)
+	}
+	v, err := node.Eval(NewEnv())
+	if err != nil {
+		t.Fatalf("eval(%q): %v", src, err)
+	}
+	return v
+}
+
+func TestPowRightAssociative(t *testing.T) {
+	got := evalSource(t, "2 ** 3 ** 2")
+	if got.asFloat() != 512 {
+		t.Errorf("2 ** 3 ** 2 = %v, want 512", got)
+	}
+}
+
+func TestUnaryMinusLooserThanPow(t *testing.T) {
+	got := evalSource(t, "-2 ** 2")
+	if got.asFloat() != -4 {
+		t.Errorf("-2 ** 2 = %v, want -4", got)
+	}
+}
+
+func TestUnaryMinusTighterThanMul(t *testing.T) {
+	got := evalSource(t, "-2 * 3")
+	if got.asFloat() != -6 {
+		t.Errorf("-2 * 3 = %v, want -6", got)
+	}
+}
+
+func TestAddSubLeftAssociative(t *testing.T) {
+	got := evalSource(t, "10 - 2 - 3")
+	if got.asFloat() != 5 {
+		t.Errorf("10 - 2 - 3 = %v, want 5", got)
+	}
+}
+
+func TestModAndBitwisePrecedence(t *testing.T) {
+	got := evalSource(t, "7 % 3 + 1")
+	if got.asFloat() != 2 {
+		t.Errorf("7 %% 3 + 1 = %v, want 2", got)
+	}
+
+	got = evalSource(t, "1 | 2 & 3")
+	if got.asFloat() != 3 {
+		t.Errorf("1 | 2 & 3 = %v, want 3 (& binds tighter than |)", got)
+	}
+}
+
+func TestUnterminatedStringIsParseError(t *testing.T) {
+	parser := NewParser(NewLexer(`"abc`))
+	_, err := parser.Parse()
+	if err == nil {
+		t.Fatal(`parse(\"abc) = nil error, want a ParseError`)
+	}
+	perr, ok := err.(*ParseError)
+	if !ok {
+		t.Fatalf("parse error type = %T, want *ParseError", err)
+	}
+	if perr.Pos.Line != 1 || perr.Pos.Column != 1 {
+		t.Errorf("parse error pos = %+v, want the opening quote at 1:1", perr.Pos)
+	}
+}
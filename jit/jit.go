@@ -0,0 +1,202 @@
+//go:build (linux || darwin) && amd64
+
+// Package jit compiles a parsed expression AST directly to x86-64
+// machine code and executes it from an mmap'd executable page, as a
+// faster alternative to ast.Node's tree-walking Eval.
+package jit
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"runtime"
+	"syscall"
+	"unsafe"
+
+	"github.com/BlueGummi/basic-asts/ast"
+)
+
+const signMask uint64 = 0x8000000000000000
+
+// compiler accumulates machine code for a single expression. Every
+// compiled sub-expression leaves its float64 result pushed onto the
+// native stack, so BinaryOpNode and UnaryOpNode can simply pop their
+// operands back off in reverse order (right operand first, then left).
+type compiler struct {
+	code []byte
+}
+
+// Compile walks node and returns a function that evaluates it natively.
+// Only the arithmetic subset of the AST (LiteralNode, BinaryOpNode,
+// UnaryOpNode) is supported; anything else returns an error so callers
+// can fall back to node.Eval.
+func Compile(node ast.Node) (func() float64, error) {
+	c := &compiler{}
+
+	c.emit(0x55)             // push rbp
+	c.emit(0x48, 0x89, 0xE5) // mov rbp, rsp
+
+	if err := c.compileNode(node); err != nil {
+		return nil, err
+	}
+	c.emitPopXMM0() // final result into xmm0
+
+	c.emit(0x48, 0x89, 0xEC) // mov rsp, rbp
+	c.emit(0x5D)             // pop rbp
+	c.emit(0xC3)             // ret
+
+	return c.assemble()
+}
+
+func (c *compiler) emit(b ...byte) {
+	c.code = append(c.code, b...)
+}
+
+func (c *compiler) compileNode(node ast.Node) error {
+	switch n := node.(type) {
+	case *ast.LiteralNode:
+		if n.Val.Type != ast.TypeInt && n.Val.Type != ast.TypeFloat {
+			return fmt.Errorf("jit: unsupported literal type %s", n.Val.Type)
+		}
+		c.emitPushNumber(numericValue(n.Val))
+		return nil
+	case *ast.BinaryOpNode:
+		if err := c.compileNode(n.Left); err != nil {
+			return err
+		}
+		if err := c.compileNode(n.Right); err != nil {
+			return err
+		}
+		return c.emitBinaryOp(n.Operator)
+	case *ast.UnaryOpNode:
+		if err := c.compileNode(n.Right); err != nil {
+			return err
+		}
+		return c.emitUnaryOp(n.Operator)
+	default:
+		return fmt.Errorf("jit: unsupported node type %T", node)
+	}
+}
+
+// numericValue unwraps an int or float ast.Value to a float64. Callers
+// must only pass values already confirmed to be numeric.
+func numericValue(v ast.Value) float64 {
+	if v.Type == ast.TypeInt {
+		return float64(v.Int)
+	}
+	return v.Float
+}
+
+// emitPushNumber loads an immediate into XMM0 and pushes it:
+// movabs rax, imm64; movq xmm0, rax; push xmm0.
+func (c *compiler) emitPushNumber(v float64) {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], math.Float64bits(v))
+
+	c.emit(0x48, 0xB8) // movabs rax, imm64
+	c.emit(buf[:]...)
+	c.emit(0x66, 0x48, 0x0F, 0x6E, 0xC0) // movq xmm0, rax
+
+	c.emitPushXMM0()
+}
+
+// emitPushXMM0 emits: sub rsp, 8; movsd [rsp], xmm0.
+func (c *compiler) emitPushXMM0() {
+	c.emit(0x48, 0x83, 0xEC, 0x08)
+	c.emit(0xF2, 0x0F, 0x11, 0x04, 0x24)
+}
+
+// emitPopXMM0 emits: movsd xmm0, [rsp]; add rsp, 8.
+func (c *compiler) emitPopXMM0() {
+	c.emit(0xF2, 0x0F, 0x10, 0x04, 0x24)
+	c.emit(0x48, 0x83, 0xC4, 0x08)
+}
+
+// emitPopXMM1 emits: movsd xmm1, [rsp]; add rsp, 8.
+func (c *compiler) emitPopXMM1() {
+	c.emit(0xF2, 0x0F, 0x10, 0x0C, 0x24)
+	c.emit(0x48, 0x83, 0xC4, 0x08)
+}
+
+func (c *compiler) emitBinaryOp(op string) error {
+	c.emitPopXMM1() // right
+	c.emitPopXMM0() // left
+
+	switch op {
+	case ast.TokenPlus:
+		c.emit(0xF2, 0x0F, 0x58, 0xC1) // addsd xmm0, xmm1
+	case ast.TokenMinus:
+		c.emit(0xF2, 0x0F, 0x5C, 0xC1) // subsd xmm0, xmm1
+	case ast.TokenMultiply:
+		c.emit(0xF2, 0x0F, 0x59, 0xC1) // mulsd xmm0, xmm1
+	case ast.TokenDivide:
+		c.emit(0xF2, 0x0F, 0x5E, 0xC1) // divsd xmm0, xmm1
+	default:
+		return fmt.Errorf("jit: unsupported operator %q", op)
+	}
+
+	c.emitPushXMM0()
+	return nil
+}
+
+// emitUnaryOp handles unary minus by flipping XMM0's sign bit against the
+// mask loaded into XMM1 register-to-register (xorpd xmm0, xmm1). xorpd's
+// memory form requires a 16-byte-aligned 128-bit operand, which a mask
+// constant appended to the code buffer at an arbitrary offset can't
+// guarantee, so the mask is built in a GPR and moved into XMM1 instead.
+func (c *compiler) emitUnaryOp(op string) error {
+	if op != ast.TokenMinus {
+		return fmt.Errorf("jit: unsupported unary operator %q", op)
+	}
+
+	c.emitPopXMM0()
+
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], signMask)
+	c.emit(0x48, 0xB8) // movabs rax, imm64
+	c.emit(buf[:]...)
+	c.emit(0x66, 0x48, 0x0F, 0x6E, 0xC8) // movq xmm1, rax
+	c.emit(0x66, 0x0F, 0x57, 0xC1)       // xorpd xmm0, xmm1
+
+	c.emitPushXMM0()
+	return nil
+}
+
+// assemble copies the finished code into an mmap'd RWX page and wraps
+// it in a trampoline that jumps straight into the machine code. The page
+// is unmapped by a finalizer once the returned func is no longer
+// reachable, so repeated Compile calls (e.g. one per REPL line) don't
+// leak a page each.
+func (c *compiler) assemble() (func() float64, error) {
+	mem, err := syscall.Mmap(-1, 0, len(c.code),
+		syscall.PROT_READ|syscall.PROT_WRITE|syscall.PROT_EXEC,
+		syscall.MAP_PRIVATE|syscall.MAP_ANONYMOUS)
+	if err != nil {
+		return nil, fmt.Errorf("jit: mmap: %w", err)
+	}
+	copy(mem, c.code)
+
+	// mem's backing array is mmap'd, not Go-heap-allocated, so it can't
+	// carry its own finalizer directly. Tie the unmap to a Go-allocated
+	// sentinel instead, kept alive for exactly as long as the returned
+	// func by a KeepAlive on every call.
+	sentinel := new(byte)
+	runtime.SetFinalizer(sentinel, func(*byte) {
+		syscall.Munmap(mem)
+	})
+
+	// A Go func value is a pointer to a pointer to the code, so converting
+	// the raw code address directly to func() would make Go dereference
+	// the code's own first bytes as if they were its entry address. Take
+	// the address of a variable that holds the code pointer instead, and
+	// convert that.
+	codePtr := unsafe.Pointer(&mem[0])
+	codePtrPtr := &codePtr
+	fn := *(*func() float64)(unsafe.Pointer(&codePtrPtr))
+
+	return func() float64 {
+		result := fn()
+		runtime.KeepAlive(sentinel)
+		return result
+	}, nil
+}
@@ -0,0 +1,60 @@
+//go:build (linux || darwin) && amd64
+
+package jit
+
+import (
+	"testing"
+
+	"github.com/BlueGummi/basic-asts/ast"
+)
+
+// benchExpr builds (2 + 3) * (4 - 1) / 5 as an AST by hand, with the
+// same tree shape the parser would produce for that source text but with
+// float literals (the parser makes int literals for these numbers).
+func benchExpr() ast.Node {
+	sum := &ast.BinaryOpNode{
+		Left:     &ast.LiteralNode{Val: ast.FloatValue(2)},
+		Operator: ast.TokenPlus,
+		Right:    &ast.LiteralNode{Val: ast.FloatValue(3)},
+	}
+	diff := &ast.BinaryOpNode{
+		Left:     &ast.LiteralNode{Val: ast.FloatValue(4)},
+		Operator: ast.TokenMinus,
+		Right:    &ast.LiteralNode{Val: ast.FloatValue(1)},
+	}
+	product := &ast.BinaryOpNode{
+		Left:     sum,
+		Operator: ast.TokenMultiply,
+		Right:    diff,
+	}
+	return &ast.BinaryOpNode{
+		Left:     product,
+		Operator: ast.TokenDivide,
+		Right:    &ast.LiteralNode{Val: ast.FloatValue(5)},
+	}
+}
+
+func BenchmarkTreeWalk(b *testing.B) {
+	node := benchExpr()
+	env := ast.NewEnv()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := node.Eval(env); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkJIT(b *testing.B) {
+	node := benchExpr()
+	fn, err := Compile(node)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = fn()
+	}
+}
@@ -0,0 +1,59 @@
+//go:build (linux || darwin) && amd64
+
+package jit
+
+import (
+	"testing"
+
+	"github.com/BlueGummi/basic-asts/ast"
+)
+
+func evalJIT(t *testing.T, src string) float64 {
+	t.Helper()
+	parser := ast.NewParser(ast.NewLexer(src))
+	node, err := parser.Parse()
+	if err != nil {
+		t.Fatalf("parse(%q): %v", src, err)
+	}
+	fn, err := Compile(node)
+	if err != nil {
+		t.Fatalf("compile(%q): %v", src, err)
+	}
+	return fn()
+}
+
+func evalTreeWalk(t *testing.T, src string) float64 {
+	t.Helper()
+	parser := ast.NewParser(ast.NewLexer(src))
+	node, err := parser.Parse()
+	if err != nil {
+		t.Fatalf("parse(%q): %v", src, err)
+	}
+	v, err := node.Eval(ast.NewEnv())
+	if err != nil {
+		t.Fatalf("eval(%q): %v", src, err)
+	}
+	if v.Type != ast.TypeInt && v.Type != ast.TypeFloat {
+		t.Fatalf("eval(%q) = %v, not numeric", src, v)
+	}
+	return numericValue(v)
+}
+
+func TestCompileMatchesTreeWalk(t *testing.T) {
+	exprs := []string{
+		"2 + 3",
+		"-5",
+		"-5 + 2",
+		"-(2 + 3)",
+		"2 - -3",
+		"(2 + 3) * (4 - 1) / 5",
+	}
+
+	for _, src := range exprs {
+		want := evalTreeWalk(t, src)
+		got := evalJIT(t, src)
+		if got != want {
+			t.Errorf("Compile(%q)() = %v, want %v (tree-walk)", src, got, want)
+		}
+	}
+}
@@ -0,0 +1,15 @@
+//go:build !((linux || darwin) && amd64)
+
+package jit
+
+import (
+	"errors"
+
+	"github.com/BlueGummi/basic-asts/ast"
+)
+
+// Compile reports that native compilation isn't available on this
+// OS/arch; callers should fall back to node.Eval.
+func Compile(node ast.Node) (func() float64, error) {
+	return nil, errors.New("jit: not supported on this platform")
+}
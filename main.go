@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/BlueGummi/basic-asts/ast"
+	"github.com/BlueGummi/basic-asts/jit"
+)
+
+var useJIT = flag.Bool("jit", false, "compile expressions to native code instead of tree-walking them")
+
+// evalLine parses and evaluates a single line of input against env,
+// reporting parse errors with a caret under the offending position.
+func evalLine(line string, env *ast.Env) {
+	parser := ast.NewParser(ast.NewLexer(line))
+	node, err := parser.Parse()
+	if err != nil {
+		reportError(line, err)
+		return
+	}
+
+	result, err := evalNode(node, env)
+	if err != nil {
+		reportError(line, err)
+		return
+	}
+
+	if _, isAssign := node.(*ast.AssignNode); !isAssign {
+		fmt.Println("out>", result)
+	}
+}
+
+// evalNode runs node through the JIT when -jit is set, falling back to
+// the tree-walking evaluator for anything the JIT can't compile (the
+// JIT only understands the numeric subset of the language).
+func evalNode(node ast.Node, env *ast.Env) (ast.Value, error) {
+	if !*useJIT {
+		return node.Eval(env)
+	}
+
+	fn, err := jit.Compile(node)
+	if err != nil {
+		return node.Eval(env)
+	}
+	return ast.FloatValue(fn()), nil
+}
+
+func reportError(line string, err error) {
+	if perr, ok := err.(*ast.ParseError); ok {
+		indent := perr.Pos.Column - 1
+		if indent < 0 {
+			indent = 0
+		}
+		fmt.Println(line)
+		fmt.Println(strings.Repeat(" ", indent) + "^ " + perr.Msg)
+		return
+	}
+	fmt.Println("err>", err)
+}
+
+func main() {
+	flag.Parse()
+
+	env := ast.NewEnv()
+	scanner := bufio.NewScanner(os.Stdin)
+
+	fmt.Print("in> ")
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if line == ".quit" {
+			break
+		}
+		if line == "" {
+			fmt.Print("in> ")
+			continue
+		}
+
+		evalLine(line, env)
+		fmt.Print("in> ")
+	}
+}